@@ -4,12 +4,15 @@ package client
 import (
 	"bytes"
 	"encoding/binary"
-	"log"
 	"time"
 )
 
 type AudioClient struct {
 	Client *Client
+
+	// decoder carries ADPCM index/prev state across packets, since
+	// IMA-ADPCM decoding is stateful from one packet to the next.
+	decoder ImaAdpcmDecoder
 }
 
 type AudioPacket struct {
@@ -17,6 +20,13 @@ type AudioPacket struct {
 	Sequence int32
 	SMeter   uint16
 	Samples  []int16
+
+	// Reconnected marks a synthetic packet (no Samples) sent in place of
+	// the swallowed ReconnectedTag message, so consumers that track
+	// Sequence across packets (e.g. recorder's gap detection) can tell a
+	// fresh session's restarted sequence numbering apart from an actual
+	// run of lost packets.
+	Reconnected bool
 }
 
 func NewAudioClient(client *Client) *AudioClient {
@@ -36,8 +46,22 @@ func (ac *AudioClient) BackgroundPlayForDuration(d time.Duration) <-chan AudioPa
 			case <-stop:
 				ac.Client.HangUp()
 				return
-			case msg := <-ac.Client.Messages:
-				if msg.Err == nil && msg.Tag == "SND" {
+			case msg, ok := <-ac.Client.Messages:
+				switch {
+				case !ok:
+					// The Client was Closed elsewhere (or its ctx ended); its
+					// supervisor is done and Messages won't yield more.
+					return
+				case msg.Err != nil:
+					// Ignore; the client's reconnect supervisor handles it.
+				case msg.Tag == ReconnectedTag:
+					// ADPCM decoder state doesn't survive a reconnect.
+					ac.decoder = ImaAdpcmDecoder{}
+					// Surface the reconnect itself: a consumer tracking
+					// Sequence needs to know it just restarted, not just
+					// that decoding is fine again.
+					out <- AudioPacket{Reconnected: true}
+				case msg.Tag == "SND":
 					out <- ac.ExtractAudioFromMessage(msg)
 				}
 			}
@@ -76,14 +100,15 @@ func (ac *AudioClient) ExtractAudioFromMessage(msg Message) AudioPacket {
 	}
 
 	// Now we stop using bb.
-	// Now we use raw encoded audio bytes bs, for efficiency inside the loop.
+	// Now we use raw encoded audio bytes, for efficiency inside the loop.
 	var samples []int16
-	bs := msg.Payload[7:]
 	if ac.Client.Compress {
-		n := len(bs) * 2
-		samples = make([]int16, n)
-		log.Fatal("decompression not implemented yet")
+		// The compressed payload carries a 10-byte ADPCM-specific prefix
+		// (not just the 7-byte Flag/Sequence/SMeter header above) before
+		// the nibble stream begins.
+		samples = ac.decoder.Decode(msg.Payload, 10)
 	} else {
+		bs := msg.Payload[7:]
 		n := len(bs) / 2
 		samples = make([]int16, n)
 		for i := 0; i < n; i++ {
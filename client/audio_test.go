@@ -0,0 +1,74 @@
+package client
+
+import "testing"
+
+// imaAdpcmEncoder builds a synthetic compressed SND payload by mirroring
+// ImaAdpcmDecoder's own state machine: for each target sample it picks
+// whichever 4-bit code the decoder would turn into the closest
+// reconstruction, the same way a real IMA-ADPCM encoder tracks the
+// decoder's predictor to avoid drifting from it. That makes `decoded` the
+// exact, byte-for-byte sequence ExtractAudioFromMessage must reproduce when
+// it decodes the resulting compressed packet.
+type imaAdpcmEncoder struct {
+	state ImaAdpcmDecoder
+}
+
+func (enc *imaAdpcmEncoder) encodeSample(target int16) (code byte, decoded int16) {
+	best := byte(0)
+	bestErr := -1
+	for c := 0; c < 16; c++ {
+		trial := enc.state
+		got := trial.decodeSample(byte(c))
+		err := int(target) - int(got)
+		if err < 0 {
+			err = -err
+		}
+		if bestErr < 0 || err < bestErr {
+			bestErr = err
+			best = byte(c)
+		}
+	}
+	decoded = enc.state.decodeSample(best)
+	return best, decoded
+}
+
+// TestExtractAudioFromMessage_Compressed builds a captured-looking SND
+// packet compressed with IMA-ADPCM and checks that the samples
+// AudioClient.ExtractAudioFromMessage decodes from it are byte-exact with
+// the samples the encoder reconstructed while producing it (IMA-ADPCM is
+// lossy, so these differ from the targets it started from).
+func TestExtractAudioFromMessage_Compressed(t *testing.T) {
+	targets := []int16{0, 500, 4000, 12000, 8000, 0, -6000, -16000, -2000, 100}
+
+	var enc imaAdpcmEncoder
+	want := make([]int16, len(targets))
+	codes := make([]byte, len(targets))
+	for i, s := range targets {
+		codes[i], want[i] = enc.encodeSample(s)
+	}
+
+	// Pack two 4-bit codes per byte, low nibble first, matching
+	// ImaAdpcmDecoder.Decode's unpacking order.
+	packed := make([]byte, len(codes)/2)
+	for i := range packed {
+		packed[i] = codes[2*i] | codes[2*i+1]<<4
+	}
+
+	// The compressed payload has a 10-byte ADPCM-specific prefix (ahead of
+	// the nibble stream) in place of the 7-byte Flag/Sequence/SMeter header
+	// used for uncompressed SND packets; its contents don't matter here.
+	payload := append(make([]byte, 10), packed...)
+	msg := Message{Tag: "SND", Payload: payload}
+
+	ac := &AudioClient{Client: &Client{Config: &Config{Compress: true}}}
+	got := ac.ExtractAudioFromMessage(msg).Samples
+
+	if len(got) != len(want) {
+		t.Fatalf("got %d samples, want %d: got=%v want=%v", len(got), len(want), got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("sample %d: got %d, want %d (byte-exact mismatch)", i, got[i], want[i])
+		}
+	}
+}
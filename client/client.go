@@ -1,10 +1,12 @@
 package client
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"log"
+	"math/rand"
 	"net/url"
 	"strings"
 	"sync"
@@ -21,6 +23,23 @@ const (
 	W_F      // Waterfall connection.
 )
 
+// ReconnectedTag is the Tag of the sentinel Message the supervisor goroutine
+// sends on Messages right after it re-establishes a dropped connection.
+// Consumers like AudioClient watch for it to reset any per-connection state
+// (e.g. the ADPCM decoder's index/prev) that doesn't survive a reconnect.
+const ReconnectedTag = "RECONNECTED"
+
+const (
+	readTimeout  = 60 * time.Second
+	writeTimeout = 10 * time.Second
+)
+
+var (
+	errBadPassword = errors.New("BAD_PASSWORD")
+	errTooBusy     = errors.New("SERVER_TOO_BUSY")
+	errServerDown  = errors.New("SERVER_DOWN")
+)
+
 type Config struct {
 	ServerHost  string
 	Password    string
@@ -30,6 +49,16 @@ type Config struct {
 	NoWaterfall bool
 	AGC         bool
 	ManGain     int
+
+	// MinBackoff and MaxBackoff bound the exponential backoff between
+	// reconnect attempts.  Zero picks sane defaults.
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+
+	// KeepaliveTimeout, if nonzero, is how long a SND connection will
+	// wait without receiving an SND frame before assuming the connection
+	// is dead and forcing a reconnect.
+	KeepaliveTimeout time.Duration
 }
 
 type Tuning struct {
@@ -65,8 +94,11 @@ type Client struct {
 	Info      map[string]string
 	Messages  <-chan Message
 
-	conn  *websocket.Conn
+	ctx    context.Context
+	cancel context.CancelFunc
+
 	mutex sync.Mutex
+	conn  *websocket.Conn
 }
 
 type Message struct {
@@ -91,7 +123,15 @@ func GetClientNum() int64 {
 
 var dialMutex sync.Mutex
 
-func Dial(cf *Config, tun *Tuning) *Client {
+// Dial connects to the KiwiSDR described by cf and tun and starts a
+// supervisor goroutine that keeps the connection alive for the life of ctx:
+// on any read error, protocol error, or keepalive timeout it reconnects with
+// exponential backoff, re-running the login/SET sequence (including the
+// current Tuning) and emitting a ReconnectedTag message on Messages.  A
+// BAD_PASSWORD rejection is the exception: the supervisor gives up and
+// closes the Client instead of reconnecting.
+// Cancel ctx, or call Client.Close, to tear the connection down for good.
+func Dial(ctx context.Context, cf *Config, tun *Tuning) (*Client, error) {
 	// We get errors back from the KiwiSDR if multiple clients try to connect at once.
 	// So use a mutex to space it out.
 	dialMutex.Lock()
@@ -100,17 +140,58 @@ func Dial(cf *Config, tun *Tuning) *Client {
 		dialMutex.Unlock()
 	}()
 
-	messages := make(chan Message, 100)
 	if cf.ManGain == 0 {
 		cf.ManGain = 50
 	}
+	if cf.MinBackoff == 0 {
+		cf.MinBackoff = 1 * time.Second
+	}
+	if cf.MaxBackoff == 0 {
+		cf.MaxBackoff = 30 * time.Second
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	messages := make(chan Message, 100)
 	c := &Client{
 		Config:    cf,
 		Tuning:    tun,
 		ClientNum: GetClientNum(),
 		Info:      make(map[string]string),
 		Messages:  messages,
+		ctx:       ctx,
+		cancel:    cancel,
 	}
+
+	conn, err := c.dialOnce()
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	c.setConn(conn)
+	c.login()
+
+	go c.supervise(messages)
+	return c, nil
+}
+
+// Context returns the Context this Client is bound to; it is Done once the
+// Client has been Closed or its parent ctx (passed to Dial) is done.
+func (c *Client) Context() context.Context {
+	return c.ctx
+}
+
+// Close tears down the connection for good and stops the reconnect
+// supervisor.  It is safe to call more than once.
+func (c *Client) Close() {
+	c.cancel()
+}
+
+// HangUp is a synonym for Close, kept for existing callers.
+func (c *Client) HangUp() {
+	c.Close()
+}
+
+func (c *Client) dialOnce() (*websocket.Conn, error) {
 	kind := "?"
 	switch c.Kind {
 	case SND:
@@ -119,111 +200,249 @@ func Dial(cf *Config, tun *Tuning) *Client {
 		kind = "W_F"
 	}
 	path := fmt.Sprintf("/%d/%s", c.ClientNum, kind)
-	u := url.URL{Scheme: "ws", Host: cf.ServerHost, Path: path}
+	u := url.URL{Scheme: "ws", Host: c.ServerHost, Path: path}
 	log.Printf("connecting to %s", u.String())
-	conn, _, err := websocket.DefaultDialer.Dial(u.String(), nil)
+	conn, _, err := websocket.DefaultDialer.DialContext(c.ctx, u.String(), nil)
 	if err != nil {
-		log.Fatalln("dial:", err)
+		return nil, fmt.Errorf("dial: %w", err)
 	}
+	return conn, nil
+}
+
+func (c *Client) setConn(conn *websocket.Conn) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
 	c.conn = conn
+}
+
+func (c *Client) currentConn() *websocket.Conn {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.conn
+}
+
+// CurrentTuning returns a copy of the Tuning currently in effect,
+// synchronized against a concurrent SetTuning (e.g. a live Retune racing a
+// reconnect).
+func (c *Client) CurrentTuning() Tuning {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return *c.Tuning
+}
+
+// SetTuning changes the Tuning this Client (and its reconnect supervisor's
+// next login) uses, synchronized against concurrent reads from login.
+// Callers still need to send their own "SET mod=..." command to retune the
+// live connection; this just keeps the shared Tuning consistent for the
+// next reconnect.
+func (c *Client) SetTuning(t Tuning) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	*c.Tuning = t
+}
+
+// login sends the login/SET sequence.  This is what kiwiclient.py sends, in
+// this order; it runs both on the initial connect and after every reconnect.
+func (c *Client) login() {
+	c.Sendf("SET auth t=kiwi p=%s", url.QueryEscape(c.Password))
+
+	c.Send("SET AR OK in=12000 out=44100") // TODO: what does this mean?
+	c.Send("SET squelch=0 max=0")
+	c.Send("SET lms_autonotch=0")
+	c.Send("SET genattn=0")
+	c.Send("SET gen=0 mix=-1")
+	c.Sendf("SET ident_user=%s", url.QueryEscape(c.Identify))
+
+	tun := c.CurrentTuning()
+	if tun.Freq > 0 {
+		c.Sendf("SET mod=%s low_cut=%d high_cut=%d freq=%.3f",
+			tun.ModeName,
+			tun.LowCut,
+			tun.HighCut,
+			float64(tun.Freq+int64(tun.Offset))/1000.0)
+	}
+
+	c.Sendf("SET agc=%d hang=0 thresh=-100 slope=6 decay=1000 manGain=%d", bool2int(c.AGC), c.ManGain)
+	c.Sendf("SET compression=%d", bool2int(c.Compress))
+	c.Send("SET OVERRIDE inactivity_timeout=0")
+}
+
+// supervise owns the connection for the life of the Client.  It runs the
+// receive loop and, whenever that loop ends for a transient reason, waits
+// out a backoff, reconnects, re-logs-in, and tells consumers about it via a
+// ReconnectedTag message before resuming.
+func (c *Client) supervise(messages chan Message) {
+	defer close(messages)
+	backoff := c.MinBackoff
+	for {
+		err := c.receiveLoop(messages)
+		if c.ctx.Err() != nil {
+			return
+		}
+		if errors.Is(err, errBadPassword) {
+			log.Printf("giving up: %v", err)
+			c.cancel()
+			return
+		}
+		log.Printf("connection lost: %v; reconnecting in %v", err, backoff)
+
+		select {
+		case <-c.ctx.Done():
+			return
+		case <-time.After(jitter(backoff)):
+		}
+		backoff = nextBackoff(backoff, c.MaxBackoff)
+
+		conn, derr := c.dialOnce()
+		if derr != nil {
+			log.Printf("reconnect dial: %v", derr)
+			continue
+		}
+		c.setConn(conn)
+		c.login()
+		messages <- Message{Tag: ReconnectedTag}
+		backoff = c.MinBackoff
+	}
+}
+
+func nextBackoff(cur, max time.Duration) time.Duration {
+	cur *= 2
+	if cur > max {
+		cur = max
+	}
+	return cur
+}
+
+// jitter returns a random duration in [d/2, d], so many clients backing off
+// at once don't all redial in lockstep.
+func jitter(d time.Duration) time.Duration {
+	if d <= 0 {
+		return 0
+	}
+	return d/2 + time.Duration(rand.Int63n(int64(d)/2+1))
+}
+
+// receiveLoop reads from the current connection until a read error, a
+// protocol-level error in a MSG frame, a keepalive timeout, or ctx is done.
+// It returns the reason the loop ended.
+func (c *Client) receiveLoop(messages chan Message) error {
+	conn := c.currentConn()
+	done := make(chan error, 1)
+
+	var keepaliveTimer *time.Timer
+	var keepaliveC <-chan time.Time
+	if c.Kind == SND && c.KeepaliveTimeout > 0 {
+		keepaliveTimer = time.NewTimer(c.KeepaliveTimeout)
+		keepaliveC = keepaliveTimer.C
+		defer keepaliveTimer.Stop()
+	}
 
 	go func() {
-		defer func() {
-			close(messages)
-			log.Printf("CloseGoingAway...")
-			c.mutex.Lock()
-			defer c.mutex.Unlock()
-			err = c.conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseGoingAway, ""))
-			if err != nil {
-				log.Printf("write CloseGoingAway: %v", err)
-			}
-		}()
 	ReceiveLoop:
 		for {
+			conn.SetReadDeadline(time.Now().Add(readTimeout))
 			_, bb, err := conn.ReadMessage()
 			if err != nil {
 				messages <- Message{Err: err}
-				log.Println("read:", err)
-				break ReceiveLoop
+				done <- err
+				return
 			}
 			if len(bb) < 64 {
 				log.Printf("recv: %q", bb)
 			}
 			if len(bb) < 3 {
-				messages <- Message{Err: errors.New("received message too short")}
-				log.Println("received message too short")
-				break ReceiveLoop
+				err := errors.New("received message too short")
+				messages <- Message{Err: err}
+				done <- err
+				return
 			}
 
-			// Always spilt the tag from the payload and send it on the messages channel.
+			// Always split the tag from the payload and send it on the messages channel.
 			tag := string(bb[0:3])
 			payload := bb[3:]
 			messages <- Message{Tag: tag, Payload: payload}
 
-			// Special MSG handling.  Add new info to info, and check for error messages.
-			switch tag {
-			case "MSG":
-				params := strings.Split(string(payload), " ")
-				for _, p := range params {
-					if p == "" {
-						continue
-					}
-					kv := strings.SplitN(p, "=", 2)
-					switch len(kv) {
-					case 0:
-						continue
-					case 1:
-						c.Info[kv[0]] = ""
-					case 2:
-						if kv[0] == "load_cfg" {
-							// Decode extra-long encoded json mesage.
-							// The parts will get added to info.
-							decodeLoadCfg(kv[1], c.Info)
-						} else {
-							// Just add other messages to info.
-							c.Info[kv[0]] = kv[1]
-						}
-					}
-				}
-				if _, ok := c.Info["too_busy"]; ok {
-					messages <- Message{Err: errors.New("SERVER_TOO_BUSY")}
-					break ReceiveLoop
-				}
-				if val, ok := c.Info["badp"]; ok && val == "1" {
-					messages <- Message{Err: errors.New("BAD_PASSWORD")}
-					break ReceiveLoop
-				}
-				if _, ok := c.Info["down"]; ok {
-					messages <- Message{Err: errors.New("SERVER_DOWN")}
+			if keepaliveTimer != nil && tag == "SND" {
+				keepaliveTimer.Reset(c.KeepaliveTimeout)
+			}
+
+			if tag == "MSG" {
+				if err := c.handleMsg(payload); err != nil {
+					messages <- Message{Err: err}
+					done <- err
 					break ReceiveLoop
 				}
 			}
 		}
 	}()
 
-	// Now that the background receiver is started, log in.
-	c.Sendf("SET auth t=kiwi p=%s", url.QueryEscape(c.Password))
-
-	// This is what kiwiclient.py sends, in this order.
-	c.Send("SET AR OK in=12000 out=44100") // TODO: what does this mean?
-	c.Send("SET squelch=0 max=0")
-	c.Send("SET lms_autonotch=0")
-	c.Send("SET genattn=0")
-	c.Send("SET gen=0 mix=-1")
-	c.Sendf("SET ident_user=%s", url.QueryEscape(c.Identify))
+	select {
+	case err := <-done:
+		// The receive goroutine already hit a read/protocol error and
+		// returned on its own; nothing else will close conn for it.
+		c.closeConn(conn)
+		return err
+	case <-keepaliveC:
+		c.closeConn(conn)
+		<-done
+		return errors.New("keepalive timeout: no SND frames received")
+	case <-c.ctx.Done():
+		c.closeConn(conn)
+		<-done
+		return c.ctx.Err()
+	}
+}
 
-	if c.Tuning.Freq > 0 {
-		c.Sendf("SET mod=%s low_cut=%d high_cut=%d freq=%.3f",
-			c.Tuning.ModeName,
-			c.Tuning.LowCut,
-			c.Tuning.HighCut,
-			float64(c.Tuning.Freq+int64(c.Tuning.Offset))/1000.0)
+// handleMsg folds a MSG frame's params into c.Info, and reports the
+// server-side conditions that mean the connection is no longer usable.
+func (c *Client) handleMsg(payload []byte) error {
+	params := strings.Split(string(payload), " ")
+	for _, p := range params {
+		if p == "" {
+			continue
+		}
+		kv := strings.SplitN(p, "=", 2)
+		switch len(kv) {
+		case 0:
+			continue
+		case 1:
+			c.Info[kv[0]] = ""
+		case 2:
+			if kv[0] == "load_cfg" {
+				// Decode extra-long encoded json mesage.
+				// The parts will get added to info.
+				decodeLoadCfg(kv[1], c.Info)
+			} else {
+				// Just add other messages to info.
+				c.Info[kv[0]] = kv[1]
+			}
+		}
 	}
+	if _, ok := c.Info["too_busy"]; ok {
+		return errTooBusy
+	}
+	if val, ok := c.Info["badp"]; ok && val == "1" {
+		return errBadPassword
+	}
+	if _, ok := c.Info["down"]; ok {
+		return errServerDown
+	}
+	return nil
+}
 
-	c.Sendf("SET agc=%d hang=0 thresh=-100 slope=6 decay=1000 manGain=%d", bool2int(c.AGC), c.ManGain)
-	c.Sendf("SET compression=%d", bool2int(c.Compress))
-	c.Send("SET OVERRIDE inactivity_timeout=0")
-	return c
+// closeConn sends a CloseGoingAway control frame and closes the connection.
+func (c *Client) closeConn(conn *websocket.Conn) {
+	if conn == nil {
+		return
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	log.Printf("CloseGoingAway...")
+	err := conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseGoingAway, ""))
+	if err != nil {
+		log.Printf("write CloseGoingAway: %v", err)
+	}
+	conn.Close()
 }
 
 func bool2int(b bool) int {
@@ -236,9 +455,14 @@ func bool2int(b bool) int {
 
 // Send the command string s to the KiwiSDR server.
 func (c *Client) Send(s string) error {
+	conn := c.currentConn()
+	if conn == nil {
+		return errors.New("not connected")
+	}
 	c.mutex.Lock()
 	defer c.mutex.Unlock()
-	return c.conn.WriteMessage(websocket.TextMessage, []byte(s))
+	conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+	return conn.WriteMessage(websocket.TextMessage, []byte(s))
 }
 
 // Send the command string, formatted with args, to the KiwiSDR server.
@@ -246,22 +470,6 @@ func (c *Client) Sendf(format string, args ...interface{}) error {
 	return c.Send(fmt.Sprintf(format, args...))
 }
 
-func (c *Client) HangUp() {
-	defer func() {
-		r := recover()
-		if r != nil {
-			log.Printf("Hangup: recover: %v", r)
-		}
-	}()
-	log.Printf("Hangup...")
-	c.mutex.Lock()
-	defer c.mutex.Unlock()
-	err := c.conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseGoingAway, ""))
-	if err != nil {
-		log.Printf("write Hangup: %v", err)
-	}
-}
-
 func decodeLoadCfg(load_cfg string, info map[string]string) {
 	a, err := url.QueryUnescape(load_cfg)
 	if err != nil {
@@ -0,0 +1,147 @@
+// +build portaudio
+
+// Package sink provides audio sinks that play a client.AudioClient's packet
+// stream directly, so callers don't need to pipe raw PCM to an external
+// player like paplay.
+package sink
+
+import (
+	"log"
+	"sync"
+
+	"github.com/gordonklaus/portaudio"
+	"github.com/strickyak/go-kiwisdr-client/client"
+)
+
+// kiwiHz is the sample rate of the AudioPacket.Samples stream coming from
+// the KiwiSDR.
+const kiwiHz = 12000
+
+// ringCapacity is the size, in samples at the device's output rate, of the
+// jitter buffer between Drain and the PortAudio callback.
+const ringCapacity = 48000 // ~1 second at 48kHz
+
+// PortAudioSink plays a <-chan client.AudioPacket through the host's default
+// output device, resampling from the KiwiSDR's 12kHz mono stream to
+// whatever rate the device prefers.
+type PortAudioSink struct {
+	stream   *portaudio.Stream
+	deviceHz int
+
+	mu       sync.Mutex
+	ring     []int16
+	readPos  int
+	writePos int
+	filled   int
+
+	// Underruns counts output samples the device callback found the ring
+	// buffer empty for (played silence instead); Overruns counts samples
+	// dropped from Drain because the ring buffer was full.
+	Underruns int
+	Overruns  int
+}
+
+// NewPortAudioSink opens the host's default output device at its preferred
+// sample rate and starts playback.
+func NewPortAudioSink() (*PortAudioSink, error) {
+	if err := portaudio.Initialize(); err != nil {
+		return nil, err
+	}
+	dev, err := portaudio.DefaultOutputDevice()
+	if err != nil {
+		portaudio.Terminate()
+		return nil, err
+	}
+
+	s := &PortAudioSink{
+		deviceHz: int(dev.DefaultSampleRate),
+		ring:     make([]int16, ringCapacity),
+	}
+
+	params := portaudio.LowLatencyParameters(nil, dev)
+	params.Output.Channels = 1
+
+	stream, err := portaudio.OpenStream(params, s.callback)
+	if err != nil {
+		portaudio.Terminate()
+		return nil, err
+	}
+	s.stream = stream
+
+	if err := stream.Start(); err != nil {
+		stream.Close()
+		portaudio.Terminate()
+		return nil, err
+	}
+	return s, nil
+}
+
+// callback runs on PortAudio's own audio thread, pulling the next block of
+// samples out of the ring buffer that Drain fills.
+func (s *PortAudioSink) callback(out []int16) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := range out {
+		if s.filled == 0 {
+			s.Underruns++
+			out[i] = 0
+			continue
+		}
+		out[i] = s.ring[s.readPos]
+		s.readPos = (s.readPos + 1) % len(s.ring)
+		s.filled--
+	}
+}
+
+// Drain reads AudioPackets from in until it is closed, resampling each to
+// the device's rate and feeding the ring buffer that callback plays from.
+func (s *PortAudioSink) Drain(in <-chan client.AudioPacket) {
+	for ap := range in {
+		samples := resample(ap.Samples, kiwiHz, s.deviceHz)
+		s.mu.Lock()
+		for _, v := range samples {
+			if s.filled == len(s.ring) {
+				s.Overruns++
+				// Drop the oldest sample to make room for live audio.
+				s.readPos = (s.readPos + 1) % len(s.ring)
+				s.filled--
+			}
+			s.ring[s.writePos] = v
+			s.writePos = (s.writePos + 1) % len(s.ring)
+			s.filled++
+		}
+		s.mu.Unlock()
+	}
+}
+
+// Close stops playback and releases the underlying PortAudio stream.
+func (s *PortAudioSink) Close() error {
+	if err := s.stream.Stop(); err != nil {
+		log.Printf("portaudio stream stop: %v", err)
+	}
+	err := s.stream.Close()
+	if terr := portaudio.Terminate(); err == nil {
+		err = terr
+	}
+	return err
+}
+
+// resample does simple linear-interpolation resampling from fromHz to toHz.
+func resample(in []int16, fromHz, toHz int) []int16 {
+	if fromHz == toHz || len(in) == 0 {
+		return in
+	}
+	n := len(in) * toHz / fromHz
+	out := make([]int16, n)
+	for i := range out {
+		srcPos := float64(i) * float64(fromHz) / float64(toHz)
+		i0 := int(srcPos)
+		if i0 >= len(in)-1 {
+			out[i] = in[len(in)-1]
+			continue
+		}
+		frac := srcPos - float64(i0)
+		out[i] = int16(float64(in[i0])*(1-frac) + float64(in[i0+1])*frac)
+	}
+	return out
+}
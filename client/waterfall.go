@@ -0,0 +1,151 @@
+// reader for KiwiSDR Waterfall (spectrum/FFT) data.
+package client
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"time"
+)
+
+// Nominal ADC clock and bin count used by the KiwiSDR waterfall protocol to
+// translate a zoom level and start bin into a center frequency and bin width.
+const (
+	kiwiAdcClockHz = 66666600.0
+	waterfallBins  = 1024
+)
+
+type WaterfallClient struct {
+	Client *Client
+
+	// decoder carries ADPCM index/prev state across packets, since
+	// IMA-ADPCM decoding is stateful from one packet to the next.
+	decoder ImaAdpcmDecoder
+
+	compress   bool
+	centerFreq float64
+	binHz      float64
+}
+
+type WaterfallPacket struct {
+	Sequence   int32
+	CenterFreq float64
+	BinHz      float64
+	Bins       []float64 // dBm
+}
+
+func NewWaterfallClient(client *Client) *WaterfallClient {
+	return &WaterfallClient{
+		Client: client,
+	}
+}
+
+// Configure sends the waterfall-specific SET commands the KiwiSDR expects:
+// the zoom level and start bin, the color-mapping dB range, the frame rate,
+// and whether the server should ADPCM-compress the bin data.
+func (wc *WaterfallClient) Configure(zoom int, startBin int64, minDb, maxDb, speed int, compress bool) {
+	wc.compress = compress
+	span := kiwiAdcClockHz / 2 / math.Pow(2, float64(zoom))
+	wc.binHz = span / waterfallBins
+	wc.centerFreq = float64(startBin)*wc.binHz + span/2
+
+	wc.Client.Sendf("SET zoom=%d start=%d", zoom, startBin)
+	wc.Client.Sendf("SET maxdb=%d mindb=%d", maxDb, minDb)
+	wc.Client.Sendf("SET wf_speed=%d", speed)
+	wc.Client.Sendf("SET wf_comp=%d", bool2int(compress))
+}
+
+func (wc *WaterfallClient) BackgroundReadForDuration(d time.Duration) <-chan WaterfallPacket {
+	out := make(chan WaterfallPacket, 500)
+	go func() {
+		defer close(out)
+		stop := time.After(d)
+		alive := time.Now().Unix()
+		for {
+			select {
+			case <-stop:
+				wc.Client.HangUp()
+				return
+			case msg, ok := <-wc.Client.Messages:
+				switch {
+				case !ok:
+					// The Client was Closed elsewhere (or its ctx ended); its
+					// supervisor is done and Messages won't yield more.
+					return
+				case msg.Err != nil:
+					// Ignore; the client's reconnect supervisor handles it.
+				case msg.Tag == ReconnectedTag:
+					// ADPCM decoder state doesn't survive a reconnect.
+					wc.decoder = ImaAdpcmDecoder{}
+				case msg.Tag == "W/F":
+					out <- wc.ExtractWaterfallFromMessage(msg)
+				}
+			}
+			if alive != time.Now().Unix() { // Once per second
+				wc.Client.Send("SET keepalive")
+				alive = time.Now().Unix()
+			}
+		}
+	}()
+	return out
+}
+
+func (wc *WaterfallClient) ExtractWaterfallFromMessage(msg Message) WaterfallPacket {
+	bb := bytes.NewBuffer(msg.Payload)
+	// First 10 bytes are header:
+	//   0: flag (unsigned byte)
+	//   1..4: sequence: little-endian signed int32
+	//   5..9: reserved
+	// Rest are dBm bins: one byte per bin, or (if wf_comp=1) an
+	// ADPCM-compressed nibble stream decoding to one byte per bin.
+	var p WaterfallPacket
+	var flag byte
+	if err := binary.Read(bb, binary.BigEndian, &flag); err != nil {
+		panic("short waterfall packet (at flag)")
+	}
+	if err := binary.Read(bb, binary.LittleEndian, &p.Sequence); err != nil {
+		panic("short waterfall packet (at Sequence)")
+	}
+
+	p.CenterFreq = wc.centerFreq
+	p.BinHz = wc.binHz
+
+	bs := msg.Payload[10:]
+	if wc.compress {
+		samples := wc.decoder.Decode(bs, 0)
+		p.Bins = make([]float64, len(samples))
+		for i, s := range samples {
+			p.Bins[i] = dbmFromWaterfallByte(descaleWaterfallSample(s))
+		}
+	} else {
+		p.Bins = make([]float64, len(bs))
+		for i, b := range bs {
+			p.Bins[i] = dbmFromWaterfallByte(b)
+		}
+	}
+	return p
+}
+
+// descaleWaterfallSample undoes the scaling a wf_comp=1 stream applies
+// before ADPCM-encoding: each 0-255 bin byte is recentered around zero and
+// spread across the full int16 range (to give the audio-range ImaAdpcmDecoder
+// enough precision to predict it well), as (b-128)*256. Decoding just
+// truncates straight to a byte, it has to be undone or bins alias/wrap
+// instead of reproducing the server's 0-255 values.
+func descaleWaterfallSample(s int16) byte {
+	v := int(s)>>8 + 128
+	switch {
+	case v < 0:
+		return 0
+	case v > 255:
+		return 255
+	default:
+		return byte(v)
+	}
+}
+
+// dbmFromWaterfallByte converts a raw waterfall bin byte, as sent by the
+// KiwiSDR server, to an approximate dBm value.
+func dbmFromWaterfallByte(b byte) float64 {
+	return float64(b)/2 - 127
+}
@@ -0,0 +1,48 @@
+package client
+
+import "testing"
+
+// TestExtractWaterfallFromMessage_Compressed builds a captured-looking W/F
+// packet compressed with IMA-ADPCM (the same imaAdpcmEncoder helper
+// audio_test.go uses, applied here to scaled bin bytes as a wf_comp=1
+// stream actually encodes them) and checks that
+// WaterfallClient.ExtractWaterfallFromMessage descales the decoded samples
+// to exactly the 0-255 bin bytes that the encoder's own reconstruction
+// descales to (IMA-ADPCM is lossy, so these can differ from the targets).
+func TestExtractWaterfallFromMessage_Compressed(t *testing.T) {
+	targets := []byte{0, 10, 64, 128, 130, 192, 245, 255}
+
+	var enc imaAdpcmEncoder
+	want := make([]byte, len(targets))
+	codes := make([]byte, len(targets))
+	for i, b := range targets {
+		scaled := int16((int(b) - 128) * 256)
+		var decoded int16
+		codes[i], decoded = enc.encodeSample(scaled)
+		want[i] = descaleWaterfallSample(decoded)
+	}
+
+	// Pack two 4-bit codes per byte, low nibble first, matching
+	// ImaAdpcmDecoder.Decode's unpacking order.
+	packed := make([]byte, len(codes)/2)
+	for i := range packed {
+		packed[i] = codes[2*i] | codes[2*i+1]<<4
+	}
+
+	// The first 10 bytes are the flag/sequence/reserved header; bin data
+	// (here, the compressed nibble stream) starts right after.
+	payload := append(make([]byte, 10), packed...)
+	msg := Message{Tag: "W/F", Payload: payload}
+
+	wc := &WaterfallClient{Client: &Client{Config: &Config{}}, compress: true}
+	got := wc.ExtractWaterfallFromMessage(msg)
+
+	if len(got.Bins) != len(want) {
+		t.Fatalf("got %d bins, want %d", len(got.Bins), len(want))
+	}
+	for i := range want {
+		if wantDbm := dbmFromWaterfallByte(want[i]); got.Bins[i] != wantDbm {
+			t.Errorf("bin %d: got %v, want %v (byte-exact mismatch)", i, got.Bins[i], wantDbm)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+/*
+kiwi-record records the KiwiSDR tuning given by --freq/--mode/--kiwi to
+--out (a .wav or .flac file, picked from the suffix) for --duration, with
+a timestamped .cue sidecar file marking tuning changes and any gaps
+patched with silence.
+
+--control, if set, starts an HTTP control endpoint (POST /add, /remove,
+/retune with JSON bodies -- see recorder.ControlServer) so more receivers
+can be added, removed, or retuned without stopping this process, for
+scheduling long unattended captures across many KiwiSDR sites from one
+process. Set --control_token unless --control is bound to a loopback or
+otherwise trusted address.
+
+It lives in its own package (rather than a top-level, build-tagged
+file) so it no longer shares a package with the other "go run
+kiwi-*.go" examples -- their flag vars and func main would otherwise
+collide when built together.
+
+Example:
+
+	go run ./cmd/kiwi-record --freq=740000 --mode=am --kiwi=sybil.yak.net \
+	  --out=740khz.flac --duration=24h --control=:8090
+*/
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/strickyak/go-kiwisdr-client/client"
+	"github.com/strickyak/go-kiwisdr-client/recorder"
+)
+
+var KIWI = flag.String("kiwi", "sybil.yak.net", "KiwiSDR server to connect to.")
+var FREQ = flag.Int64("freq", 740000, "Frequency in Hz")
+var MODE = flag.String("mode", "am", "am, cw, lsb, or usb.")
+var OUT = flag.String("out", "recording.wav", "Output file; .flac for FLAC, else WAV.")
+var DURATION = flag.Duration("duration", 366*24*time.Hour, "How long to record.")
+var CONTROL = flag.String("control", "", "If set, address (e.g. :8090) to serve the HTTP add/remove/retune control endpoint on.")
+var CONTROL_TOKEN = flag.String("control_token", "", "If set, required as an 'Authorization: Bearer <token>' header on every --control request. Strongly recommended unless --control is bound to a loopback/trusted address.")
+
+func main() {
+	flag.Parse()
+	kiwi := *KIWI
+	if !strings.Contains(kiwi, ":") {
+		kiwi += ":8073"
+	}
+
+	var mode client.Mode
+	switch *MODE {
+	case "am":
+		mode = client.AM
+	case "cw":
+		mode = client.CW
+	case "lsb":
+		mode = client.LSB
+	case "usb":
+		mode = client.USB
+	default:
+		log.Fatalf("Unknown mode name: %q", *MODE)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *DURATION)
+	defer cancel()
+
+	rec := recorder.NewRecorder(ctx)
+	spec := recorder.ReceiverSpec{
+		Config: &client.Config{
+			ServerHost:  kiwi,
+			Kind:        client.SND,
+			Identify:    "Recorder(golang)",
+			NoWaterfall: true,
+		},
+		Tuning:     client.Tuning{Freq: *FREQ, Mode: mode},
+		OutputPath: *OUT,
+	}
+	if err := rec.AddReceiver(spec); err != nil {
+		log.Fatalf("add receiver: %v", err)
+	}
+
+	if *CONTROL != "" {
+		cs := recorder.NewControlServer(rec, *CONTROL_TOKEN)
+		go func() {
+			log.Printf("control endpoint listening on %s", *CONTROL)
+			log.Fatal(http.ListenAndServe(*CONTROL, cs))
+		}()
+	}
+
+	<-ctx.Done()
+}
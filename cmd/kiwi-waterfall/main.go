@@ -0,0 +1,92 @@
+/*
+  kiwi-waterfall connects to the KiwiSDR at the --kiwi address for
+  --duration and writes a grayscale PGM spectrogram to stdout, one row
+  per waterfall frame received, newest row last.
+
+  Specify --zoom and --start to pick the span and center of the waterfall,
+  and --mindb/--maxdb to pick the dB range that maps to black/white.
+
+  It lives in its own package (rather than a top-level, build-tagged
+  file) so it no longer shares a package with the other "go run
+  kiwi-*.go" examples -- their flag vars and func main would otherwise
+  collide when built together.
+
+  Example:
+    go run ./cmd/kiwi-waterfall --kiwi=sybil.yak.net --duration=30s > wf.pgm
+*/
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/strickyak/go-kiwisdr-client/client"
+)
+
+var KIWI = flag.String("kiwi", "sybil.yak.net", "KiwiSDR server to connect to.")
+var DURATION = flag.Duration("duration", 30*time.Second, "How long to capture.")
+var ZOOM = flag.Int("zoom", 0, "Waterfall zoom level (0 is full span).")
+var START = flag.Int64("start", 0, "Waterfall start bin.")
+var MINDB = flag.Int("mindb", -110, "dB value that maps to black.")
+var MAXDB = flag.Int("maxdb", -20, "dB value that maps to white.")
+var SPEED = flag.Int("speed", 1, "Waterfall frame rate (frames per SET wf_speed interval).")
+var COMPRESS = flag.Bool("compress", false, "Ask the server to ADPCM-compress the waterfall bins.")
+
+func main() {
+	flag.Parse()
+	kiwi := *KIWI
+	if !strings.Contains(kiwi, ":") {
+		kiwi += ":8073"
+	}
+
+	var config = &client.Config{
+		ServerHost: kiwi,
+		Kind:       client.W_F,
+		Identify:   "WaterfallClient(golang)",
+	}
+
+	// Waterfall connections don't tune an audio channel.
+	var tuning = &client.Tuning{}
+
+	// Create a Kiwi websocket client.
+	c, err := client.Dial(context.Background(), config, tuning)
+	if err != nil {
+		log.Fatalf("dial: %v", err)
+	}
+	// Wrap a waterfall client around the Kiwi client.
+	wc := client.NewWaterfallClient(c)
+	wc.Configure(*ZOOM, *START, *MINDB, *MAXDB, *SPEED, *COMPRESS)
+
+	// Collect frames first, since a PGM needs its height up front.
+	var rows [][]float64
+	for wp := range wc.BackgroundReadForDuration(*DURATION) {
+		rows = append(rows, wp.Bins)
+	}
+
+	if len(rows) == 0 {
+		fmt.Fprintln(os.Stderr, "no waterfall frames received")
+		return
+	}
+
+	w := bufio.NewWriter(os.Stdout)
+	defer w.Flush()
+	fmt.Fprintf(w, "P5\n%d %d\n255\n", len(rows[0]), len(rows))
+	for _, bins := range rows {
+		for _, dbm := range bins {
+			v := (dbm - float64(*MINDB)) / float64(*MAXDB-*MINDB) * 255
+			if v < 0 {
+				v = 0
+			}
+			if v > 255 {
+				v = 255
+			}
+			w.WriteByte(byte(v))
+		}
+	}
+}
@@ -0,0 +1,216 @@
+// Package flac implements a minimal FLAC encoder: PCM is packed into
+// uncompressed VERBATIM subframes rather than actually compressed. The
+// result is a fully spec-conformant, streaming-decodable bitstream, just
+// not a compact one. recorder.flacWriter (file output, known duration) and
+// server's flacEncoder (live HTTP streaming, unknown duration) both build
+// on the pieces here.
+package flac
+
+import "encoding/binary"
+
+// MagicBytes is the 4-byte signature every FLAC stream starts with.
+const MagicBytes = "fLaC"
+
+// MetadataBlockHeader is the 4-byte header preceding a FLAC metadata block:
+// a last-block flag, a 7-bit block type, and a 24-bit length.
+func MetadataBlockHeader(isLast bool, blockType byte, length uint32) [4]byte {
+	var h [4]byte
+	h[0] = blockType & 0x7F
+	if isLast {
+		h[0] |= 0x80
+	}
+	h[1] = byte(length >> 16)
+	h[2] = byte(length >> 8)
+	h[3] = byte(length)
+	return h
+}
+
+// EncodeStreamInfo packs the 34-byte STREAMINFO metadata block body (not
+// including its 4-byte MetadataBlockHeader). Per spec, minFrame/maxFrame
+// and totalSamples may be 0 to mean "unknown", which callers writing a
+// live, unbounded stream should use.
+func EncodeStreamInfo(minBlock, maxBlock uint16, minFrame, maxFrame uint32, sampleRate uint32, channels, bps uint8, totalSamples uint64, md5sum [16]byte) []byte {
+	var bw bitWriter
+	bw.writeBits(uint64(minBlock), 16)
+	bw.writeBits(uint64(maxBlock), 16)
+	bw.writeBits(uint64(minFrame), 24)
+	bw.writeBits(uint64(maxFrame), 24)
+	bw.writeBits(uint64(sampleRate), 20)
+	bw.writeBits(uint64(channels-1), 3)
+	bw.writeBits(uint64(bps-1), 5)
+	bw.writeBits(totalSamples, 36)
+	bw.align()
+	out := append([]byte{}, bw.Bytes()...)
+	return append(out, md5sum[:]...)
+}
+
+// RawMD5Bytes returns a block's raw, un-encoded signal in the layout the
+// STREAMINFO MD5 is computed over: each sample as signed 16-bit
+// little-endian, independent of how FrameEncoder actually packs it into
+// subframes. Callers feed the result to their own MD5 hash.
+func RawMD5Bytes(samples []int16) []byte {
+	raw := make([]byte, 2*len(samples))
+	for i, s := range samples {
+		binary.LittleEndian.PutUint16(raw[2*i:], uint16(s))
+	}
+	return raw
+}
+
+// FrameEncoder packs successive blocks of mono 16-bit PCM samples into
+// VERBATIM FLAC frames at a fixed sample rate, numbering them sequentially
+// as the spec's frame header requires.
+type FrameEncoder struct {
+	SampleRate  uint32
+	frameNumber uint64
+}
+
+// EncodeFrame packs one FLAC frame holding a single VERBATIM (uncompressed)
+// subframe of samples, tagged with the next sequential frame number.
+func (fe *FrameEncoder) EncodeFrame(samples []int16) []byte {
+	var bw bitWriter
+	// The STREAMINFO-inferred codes (sample rate 0000, sample size 000) are
+	// spec-legal, but a decoder that doesn't thread STREAMINFO into its
+	// frame parser can't resolve them, so each frame header carries
+	// its own explicit sample rate and bits-per-sample instead.
+	bw.writeBits(0x3FFE, 14) // frame sync code
+	bw.writeBits(0, 1)       // reserved
+	bw.writeBits(0, 1)       // fixed-blocksize stream
+	bw.writeBits(0x7, 4)     // block size: explicit 16-bit value follows
+	bw.writeBits(0xD, 4)     // sample rate: explicit 16-bit Hz value follows
+	bw.writeBits(0x0, 4)     // channel assignment: 1 independent channel
+	bw.writeBits(0x4, 3)     // sample size: 16 bits-per-sample
+	bw.writeBits(0, 1)       // reserved
+	writeUTF8Coded(&bw, fe.frameNumber)
+	bw.writeBits(uint64(len(samples)-1), 16) // the block size this header code promised
+	bw.writeBits(uint64(fe.SampleRate), 16)  // the sample rate this header code promised
+	bw.align()
+	header := bw.Bytes()
+
+	frame := make([]byte, 0, len(header)+1+2*len(samples)+2)
+	frame = append(frame, header...)
+	frame = append(frame, crc8(header))
+
+	var sub bitWriter
+	sub.writeBits(0, 1)   // zero bit
+	sub.writeBits(0x1, 6) // SUBFRAME_VERBATIM
+	sub.writeBits(0, 1)   // no wasted bits
+	for _, s := range samples {
+		sub.writeBits(uint64(uint16(s)), 16)
+	}
+	sub.align()
+	frame = append(frame, sub.Bytes()...)
+
+	crc := crc16(frame)
+	frame = append(frame, byte(crc>>8), byte(crc))
+
+	fe.frameNumber++
+	return frame
+}
+
+// writeUTF8Coded writes n using the variable-length, UTF-8-like coding FLAC
+// uses for frame and sample numbers in the frame header.
+func writeUTF8Coded(bw *bitWriter, n uint64) {
+	switch {
+	case n < 0x80:
+		bw.writeBits(n, 8)
+	case n < 0x800:
+		bw.writeBits(0xC0|(n>>6), 8)
+		bw.writeBits(0x80|(n&0x3F), 8)
+	case n < 0x10000:
+		bw.writeBits(0xE0|(n>>12), 8)
+		bw.writeBits(0x80|((n>>6)&0x3F), 8)
+		bw.writeBits(0x80|(n&0x3F), 8)
+	case n < 0x200000:
+		bw.writeBits(0xF0|(n>>18), 8)
+		bw.writeBits(0x80|((n>>12)&0x3F), 8)
+		bw.writeBits(0x80|((n>>6)&0x3F), 8)
+		bw.writeBits(0x80|(n&0x3F), 8)
+	case n < 0x4000000:
+		bw.writeBits(0xF8|(n>>24), 8)
+		bw.writeBits(0x80|((n>>18)&0x3F), 8)
+		bw.writeBits(0x80|((n>>12)&0x3F), 8)
+		bw.writeBits(0x80|((n>>6)&0x3F), 8)
+		bw.writeBits(0x80|(n&0x3F), 8)
+	default:
+		bw.writeBits(0xFC|(n>>30), 8)
+		bw.writeBits(0x80|((n>>24)&0x3F), 8)
+		bw.writeBits(0x80|((n>>18)&0x3F), 8)
+		bw.writeBits(0x80|((n>>12)&0x3F), 8)
+		bw.writeBits(0x80|((n>>6)&0x3F), 8)
+		bw.writeBits(0x80|(n&0x3F), 8)
+	}
+}
+
+// crc8 is FLAC's frame header checksum: polynomial x^8+x^2+x^1+1, MSB
+// first, zero-initialized.
+func crc8(data []byte) byte {
+	var crc byte
+	for _, b := range data {
+		crc ^= b
+		for i := 0; i < 8; i++ {
+			if crc&0x80 != 0 {
+				crc = (crc << 1) ^ 0x07
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
+
+// crc16 is FLAC's whole-frame checksum: polynomial
+// x^16+x^15+x^2+1, MSB first, zero-initialized.
+func crc16(data []byte) uint16 {
+	var crc uint16
+	for _, b := range data {
+		crc ^= uint16(b) << 8
+		for i := 0; i < 8; i++ {
+			if crc&0x8000 != 0 {
+				crc = (crc << 1) ^ 0x8005
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
+
+// bitWriter packs values MSB-first into a growing byte slice, the way the
+// FLAC bitstream format requires.
+type bitWriter struct {
+	buf   []byte
+	cur   byte
+	nbits uint
+}
+
+func (bw *bitWriter) writeBits(value uint64, n uint) {
+	for n > 0 {
+		take := 8 - bw.nbits
+		if take > n {
+			take = n
+		}
+		shift := n - take
+		bits := byte((value >> shift) & ((1 << take) - 1))
+		bw.cur |= bits << (8 - bw.nbits - take)
+		bw.nbits += take
+		n -= take
+		if bw.nbits == 8 {
+			bw.buf = append(bw.buf, bw.cur)
+			bw.cur = 0
+			bw.nbits = 0
+		}
+	}
+}
+
+// align pads out any partial byte with zero bits.
+func (bw *bitWriter) align() {
+	if bw.nbits > 0 {
+		bw.buf = append(bw.buf, bw.cur)
+		bw.cur = 0
+		bw.nbits = 0
+	}
+}
+
+func (bw *bitWriter) Bytes() []byte {
+	return bw.buf
+}
@@ -20,6 +20,7 @@ package main
 
 import (
 	"bufio"
+	"context"
 	"flag"
 	"log"
 	"math"
@@ -98,7 +99,10 @@ func main() {
 	// Small buffer for bytes output to stdout.
 	w := bufio.NewWriterSize(os.Stdout, 512)
 	// Create a Kiwi websocket client.
-	c := client.Dial(config, tuning)
+	c, err := client.Dial(context.Background(), config, tuning)
+	if err != nil {
+		log.Fatalf("dial: %v", err)
+	}
 	// Wrap an audio client around the Kiwi client.
 	ac := client.NewAudioClient(c)
 	// Read audio packets from a goroutine that does the reading from the websocket.
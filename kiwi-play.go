@@ -0,0 +1,85 @@
+// +build main,portaudio
+
+/*
+  kiwi-play.go connects to the KiwiSDR at the --kiwi address for --duration
+  and plays the audio directly through the host's default output device via
+  PortAudio, instead of piping raw PCM to an external player like paplay.
+
+  Building this example requires CGO and the PortAudio development library
+  to be installed, and the "portaudio" build tag:
+
+    go run -tags portaudio kiwi-play.go --freq=740000 --mode=am --kiwi=sybil.yak.net
+
+  The rest of this module builds fine without CGO or PortAudio; only this
+  example and the client/sink package require them.
+*/
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/strickyak/go-kiwisdr-client/client"
+	"github.com/strickyak/go-kiwisdr-client/client/sink"
+)
+
+var KIWI = flag.String("kiwi", "sybil.yak.net", "KiwiSDR server to connect to.")
+var DURATION = flag.Duration("duration", 366*24*time.Hour, "How long to play.")
+var FREQ = flag.Int64("freq", 740000, "Frequency in Hz")
+var MODE = flag.String("mode", "am", "am, cw, lsb, usb, etc.   See switch statement.")
+var AGC = flag.Bool("agc", true, "Enable AGC in receiver")
+var MANGAIN = flag.Int("mangain", 50, "Manual Gain in SDR (if no AGC) (10 to 90?)")
+
+func main() {
+	flag.Parse()
+	kiwi := *KIWI
+	if !strings.Contains(kiwi, ":") {
+		kiwi += ":8073"
+	}
+
+	var config = &client.Config{
+		ServerHost:  kiwi,
+		Kind:        client.SND,
+		Identify:    "AudioClient(golang)",
+		AGC:         *AGC,
+		ManGain:     *MANGAIN,
+		NoWaterfall: true,
+	}
+
+	var mode client.Mode
+	switch *MODE {
+	case "am":
+		mode = client.AM
+	case "cw":
+		mode = client.CW
+	case "lsb":
+		mode = client.LSB
+	case "usb":
+		mode = client.USB
+	default:
+		log.Fatalf("Unknown mode name: %q", *MODE)
+	}
+
+	var tuning = &client.Tuning{
+		Freq: *FREQ,
+		Mode: mode,
+	}
+
+	c, err := client.Dial(context.Background(), config, tuning)
+	if err != nil {
+		log.Fatalf("dial: %v", err)
+	}
+	ac := client.NewAudioClient(c)
+
+	ps, err := sink.NewPortAudioSink()
+	if err != nil {
+		log.Fatalf("portaudio: %v", err)
+	}
+	defer ps.Close()
+
+	ps.Drain(ac.BackgroundPlayForDuration(*DURATION))
+	log.Printf("underruns=%d overruns=%d", ps.Underruns, ps.Overruns)
+}
@@ -0,0 +1,171 @@
+package recorder
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/strickyak/go-kiwisdr-client/client"
+)
+
+// ControlServer is an http.Handler that lets a scheduler add, remove, and
+// retune a Recorder's receivers at runtime, so a long unattended capture
+// spanning many KiwiSDR sites can be driven from one process without
+// restarting it.
+//
+// All three endpoints take POST requests with a JSON body:
+//
+//	POST /add     {"server_host","identify","output_path","freq","mode"}
+//	POST /remove  {"output_path"}
+//	POST /retune  {"output_path","freq","mode"}
+//
+// Whoever can reach this endpoint can make the process dial arbitrary
+// hosts (via server_host) and write files anywhere AddReceiver's output
+// path validation allows. AuthToken should always be set outside of a
+// trusted, firewalled address; callers must then send it as
+// "Authorization: Bearer <token>" on every request.
+type ControlServer struct {
+	Recorder *Recorder
+
+	// AuthToken, if non-empty, is required (as "Authorization: Bearer
+	// <token>") on every request; requests without a matching token get
+	// 401. Leaving it empty serves every request unauthenticated, which
+	// is only appropriate on a loopback or otherwise trusted address.
+	AuthToken string
+}
+
+// NewControlServer returns a ControlServer wrapping r. authToken, if
+// non-empty, is required on every request; see ControlServer.AuthToken.
+func NewControlServer(r *Recorder, authToken string) *ControlServer {
+	return &ControlServer{Recorder: r, AuthToken: authToken}
+}
+
+type addRequest struct {
+	ServerHost string `json:"server_host"`
+	Identify   string `json:"identify"`
+	OutputPath string `json:"output_path"`
+	Freq       int64  `json:"freq"`
+	Mode       string `json:"mode"`
+}
+
+type retuneRequest struct {
+	OutputPath string `json:"output_path"`
+	Freq       int64  `json:"freq"`
+	Mode       string `json:"mode"`
+}
+
+type removeRequest struct {
+	OutputPath string `json:"output_path"`
+}
+
+func (cs *ControlServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "POST only", http.StatusMethodNotAllowed)
+		return
+	}
+	if !cs.authorized(r) {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	switch r.URL.Path {
+	case "/add":
+		cs.handleAdd(w, r)
+	case "/remove":
+		cs.handleRemove(w, r)
+	case "/retune":
+		cs.handleRetune(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// authorized reports whether r carries AuthToken as a bearer token.
+// AuthToken being unset means every request is authorized.
+func (cs *ControlServer) authorized(r *http.Request) bool {
+	if cs.AuthToken == "" {
+		return true
+	}
+	const prefix = "Bearer "
+	h := r.Header.Get("Authorization")
+	if !strings.HasPrefix(h, prefix) {
+		return false
+	}
+	got := strings.TrimPrefix(h, prefix)
+	return subtle.ConstantTimeCompare([]byte(got), []byte(cs.AuthToken)) == 1
+}
+
+func (cs *ControlServer) handleAdd(w http.ResponseWriter, r *http.Request) {
+	var req addRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	mode, ok := modeByName(req.Mode)
+	if !ok {
+		http.Error(w, fmt.Sprintf("unknown mode %q", req.Mode), http.StatusBadRequest)
+		return
+	}
+	spec := ReceiverSpec{
+		Config: &client.Config{
+			ServerHost:  req.ServerHost,
+			Kind:        client.SND,
+			Identify:    req.Identify,
+			NoWaterfall: true,
+		},
+		Tuning:     client.Tuning{Freq: req.Freq, Mode: mode},
+		OutputPath: req.OutputPath,
+	}
+	if err := cs.Recorder.AddReceiver(spec); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+func (cs *ControlServer) handleRemove(w http.ResponseWriter, r *http.Request) {
+	var req removeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := cs.Recorder.RemoveReceiver(req.OutputPath); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+func (cs *ControlServer) handleRetune(w http.ResponseWriter, r *http.Request) {
+	var req retuneRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	mode, ok := modeByName(req.Mode)
+	if !ok {
+		http.Error(w, fmt.Sprintf("unknown mode %q", req.Mode), http.StatusBadRequest)
+		return
+	}
+	if err := cs.Recorder.Retune(req.OutputPath, client.Tuning{Freq: req.Freq, Mode: mode}); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+func modeByName(name string) (client.Mode, bool) {
+	switch name {
+	case "am":
+		return client.AM, true
+	case "cw":
+		return client.CW, true
+	case "lsb":
+		return client.LSB, true
+	case "usb":
+		return client.USB, true
+	default:
+		return client.Mode{}, false
+	}
+}
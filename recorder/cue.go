@@ -0,0 +1,44 @@
+package recorder
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+// cueWriter appends timestamped markers to a plain-text ".cue" sidecar file
+// next to the audio file: one line per notable event (a tuning change, a
+// gap patched with silence, a retune), each tagged with its offset into the
+// recording. This isn't a CD-style INDEX cue sheet -- there's only one
+// continuous track here -- just a simple log a listener can grep for "GAP"
+// or "RETUNE" while scrubbing a long unattended capture.
+type cueWriter struct {
+	f *os.File
+}
+
+func newCueWriter(path string) (*cueWriter, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	return &cueWriter{f: f}, nil
+}
+
+// Mark appends one marker line, flushed immediately so the sidecar stays
+// readable while the recording is still running.
+func (c *cueWriter) Mark(offset time.Duration, tag, detail string) {
+	fmt.Fprintf(c.f, "%s %s %s\n", formatCueOffset(offset), tag, detail)
+	c.f.Sync()
+}
+
+func (c *cueWriter) Close() error {
+	return c.f.Close()
+}
+
+func formatCueOffset(d time.Duration) string {
+	h := int(d / time.Hour)
+	m := int(d/time.Minute) % 60
+	s := int(d/time.Second) % 60
+	ms := int(d/time.Millisecond) % 1000
+	return fmt.Sprintf("%02d:%02d:%02d.%03d", h, m, s, ms)
+}
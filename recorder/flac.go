@@ -0,0 +1,134 @@
+package recorder
+
+import (
+	"crypto/md5"
+	"hash"
+	"os"
+
+	"github.com/strickyak/go-kiwisdr-client/flac"
+)
+
+const (
+	flacSampleRate    = 12000
+	flacChannels      = 1
+	flacBitsPerSample = 16
+	flacBlockSize     = 4096 // samples per frame, except a possible final partial frame
+)
+
+// flacWriter incrementally encodes mono 16-bit PCM into a FLAC file using
+// uncompressed VERBATIM subframes: much simpler than real FLAC compression,
+// but the result is a fully spec-conformant, streaming-decodable file with
+// a correct STREAMINFO block (finalized on Close, once the totals are
+// known).
+type flacWriter struct {
+	f            *os.File
+	streamInfoAt int64
+	enc          flac.FrameEncoder
+
+	pending []int16
+
+	totalSamples uint64
+	minBlockSize uint16
+	maxBlockSize uint16
+	minFrameSize uint32
+	maxFrameSize uint32
+	sum          hash.Hash
+}
+
+func newFlacWriter(path string) (*flacWriter, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	w := &flacWriter{f: f, enc: flac.FrameEncoder{SampleRate: flacSampleRate}, sum: md5.New()}
+	if err := w.writeHeader(); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return w, nil
+}
+
+func (w *flacWriter) writeHeader() error {
+	if _, err := w.f.WriteString(flac.MagicBytes); err != nil {
+		return err
+	}
+	hdr := flac.MetadataBlockHeader(true, 0, 34) // type 0 = STREAMINFO, the only block we emit
+	if _, err := w.f.Write(hdr[:]); err != nil {
+		return err
+	}
+	pos, err := w.f.Seek(0, os.SEEK_CUR)
+	if err != nil {
+		return err
+	}
+	w.streamInfoAt = pos
+
+	placeholder := flac.EncodeStreamInfo(0, 0, 0, 0, flacSampleRate, flacChannels, flacBitsPerSample, 0, [16]byte{})
+	_, err = w.f.Write(placeholder)
+	return err
+}
+
+// WriteSamples buffers samples and flushes complete flacBlockSize frames as
+// they fill; any remainder is flushed as a final, shorter frame on Close.
+func (w *flacWriter) WriteSamples(samples []int16) error {
+	w.pending = append(w.pending, samples...)
+	for len(w.pending) >= flacBlockSize {
+		if err := w.flushBlock(w.pending[:flacBlockSize]); err != nil {
+			return err
+		}
+		w.pending = w.pending[flacBlockSize:]
+	}
+	return nil
+}
+
+func (w *flacWriter) flushBlock(block []int16) error {
+	frame := w.enc.EncodeFrame(block)
+	if _, err := w.f.Write(frame); err != nil {
+		return err
+	}
+	w.totalSamples += uint64(len(block))
+
+	n := uint16(len(block))
+	if w.minBlockSize == 0 || n < w.minBlockSize {
+		w.minBlockSize = n
+	}
+	if n > w.maxBlockSize {
+		w.maxBlockSize = n
+	}
+	fn := uint32(len(frame))
+	if w.minFrameSize == 0 || fn < w.minFrameSize {
+		w.minFrameSize = fn
+	}
+	if fn > w.maxFrameSize {
+		w.maxFrameSize = fn
+	}
+
+	w.sum.Write(flac.RawMD5Bytes(block))
+	return nil
+}
+
+// Close flushes any buffered remainder as a final short frame, rewrites the
+// STREAMINFO block with the now-known totals, and closes the file.
+func (w *flacWriter) Close() error {
+	if len(w.pending) > 0 {
+		if err := w.flushBlock(w.pending); err != nil {
+			w.f.Close()
+			return err
+		}
+		w.pending = nil
+	}
+
+	var sum [16]byte
+	copy(sum[:], w.sum.Sum(nil))
+	final := flac.EncodeStreamInfo(w.minBlockSize, w.maxBlockSize, w.minFrameSize, w.maxFrameSize,
+		flacSampleRate, flacChannels, flacBitsPerSample, w.totalSamples, sum)
+
+	if _, err := w.f.Seek(w.streamInfoAt, os.SEEK_SET); err != nil {
+		w.f.Close()
+		return err
+	}
+	if _, err := w.f.Write(final); err != nil {
+		w.f.Close()
+		return err
+	}
+	return w.f.Close()
+}
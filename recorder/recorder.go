@@ -0,0 +1,286 @@
+// Package recorder records one or more KiwiSDR tunings to WAV or FLAC
+// files, one goroutine per receiver, with a timestamped ".cue" sidecar
+// marking tuning changes and any gaps (detected via AudioPacket.Sequence
+// discontinuities) patched with silence to keep the timeline monotonic.
+//
+// Receivers can be added, removed, and retuned at runtime via
+// ControlServer, so a single process can schedule long unattended
+// captures spanning many KiwiSDR sites.
+package recorder
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/strickyak/go-kiwisdr-client/client"
+)
+
+// maxGapPackets caps how many packets' worth of silence receiver.feed will
+// ever patch in for one gap, regardless of how large ap.Sequence's jump
+// looks. It's a backstop against sizing an allocation off a bogus or
+// adversarial sequence number; legitimate links don't drop anywhere close
+// to this many consecutive packets (each ~hundreds of ms, so this is
+// still well over an hour of silence).
+const maxGapPackets = 20000
+
+// sampleWriter is the common interface between wavWriter and flacWriter:
+// both accept a stream of mono 16-bit samples and finalize their header's
+// size/length fields on Close.
+type sampleWriter interface {
+	WriteSamples(samples []int16) error
+	Close() error
+}
+
+// ReceiverSpec describes one KiwiSDR tuning to record to its own output
+// file.
+type ReceiverSpec struct {
+	Config     *client.Config
+	Tuning     client.Tuning
+	OutputPath string // format is picked from the suffix: ".flac", else WAV
+}
+
+// Format returns "flac" or "wav", picked from OutputPath's suffix.
+func (s ReceiverSpec) Format() string {
+	if strings.HasSuffix(s.OutputPath, ".flac") {
+		return "flac"
+	}
+	return "wav"
+}
+
+func newSampleWriter(spec ReceiverSpec) (sampleWriter, error) {
+	if spec.Format() == "flac" {
+		return newFlacWriter(spec.OutputPath)
+	}
+	return newWavWriter(spec.OutputPath)
+}
+
+// receiver is one running recording: a dialed Client feeding a sampleWriter
+// and a cueWriter, keyed in Recorder.receivers by spec.OutputPath.
+type receiver struct {
+	// spec.Tuning is the exact Tuning struct client.Dial was given.
+	// Retune changes it via client.SetTuning (rather than swapping in a
+	// new Tuning) so the Client's reconnect supervisor picks up the
+	// change too; that's also what keeps the change safe to make while a
+	// reconnect's login() is concurrently reading it.
+	spec   ReceiverSpec
+	client *client.Client
+	writer sampleWriter
+	cue    *cueWriter
+	start  time.Time
+
+	mu           sync.Mutex
+	haveSequence bool
+	lastSequence int32
+	packetLen    int
+	samples      int64
+}
+
+// Recorder runs zero or more receivers, each recording one KiwiSDR tuning
+// to its own WAV or FLAC file, for the life of the ctx passed to
+// NewRecorder.
+type Recorder struct {
+	ctx context.Context
+
+	mu        sync.Mutex
+	receivers map[string]*receiver
+}
+
+// NewRecorder returns a Recorder whose receivers are all torn down when ctx
+// is done.
+func NewRecorder(ctx context.Context) *Recorder {
+	return &Recorder{
+		ctx:       ctx,
+		receivers: make(map[string]*receiver),
+	}
+}
+
+// AddReceiver dials the KiwiSDR described by spec and starts recording to
+// spec.OutputPath.  It is safe to call while other receivers are running.
+func (r *Recorder) AddReceiver(spec ReceiverSpec) error {
+	if err := validOutputPath(spec.OutputPath); err != nil {
+		return fmt.Errorf("recorder: %w", err)
+	}
+
+	r.mu.Lock()
+	if _, ok := r.receivers[spec.OutputPath]; ok {
+		r.mu.Unlock()
+		return fmt.Errorf("recorder: %s is already being recorded", spec.OutputPath)
+	}
+	// Reserve the path with a nil placeholder before doing the slow
+	// dial/file work below, so a second, concurrent AddReceiver call for
+	// the same path can't also pass the check above and race us to insert
+	// (orphaning whichever receiver loses).
+	r.receivers[spec.OutputPath] = nil
+	r.mu.Unlock()
+
+	rv, err := r.startReceiver(spec)
+
+	r.mu.Lock()
+	if err != nil {
+		delete(r.receivers, spec.OutputPath)
+		r.mu.Unlock()
+		return err
+	}
+	r.receivers[spec.OutputPath] = rv
+	r.mu.Unlock()
+
+	rv.cue.Mark(0, "TUNE", tuningLabel(rv.spec.Tuning))
+	go rv.feed()
+	return nil
+}
+
+// validOutputPath rejects an absolute path or one that, once cleaned, still
+// starts with a ".." segment (so "a/../b" is allowed, "../b" is not).
+// AddReceiver's output_path can come straight from ControlServer's
+// unauthenticated HTTP body, so it must never be allowed to escape
+// whatever directory the caller intended (e.g. "../../etc/cron.d/x") or
+// name an arbitrary absolute file.
+func validOutputPath(p string) error {
+	if filepath.IsAbs(p) {
+		return fmt.Errorf("output path %q must not be absolute", p)
+	}
+	clean := filepath.ToSlash(filepath.Clean(p))
+	if clean == ".." || strings.HasPrefix(clean, "../") {
+		return fmt.Errorf("output path %q must not escape its directory", p)
+	}
+	return nil
+}
+
+// startReceiver does the slow part of AddReceiver: opening spec's output
+// and cue files and dialing the KiwiSDR. It touches no Recorder state, so
+// it can run outside r.mu.
+func (r *Recorder) startReceiver(spec ReceiverSpec) (*receiver, error) {
+	writer, err := newSampleWriter(spec)
+	if err != nil {
+		return nil, fmt.Errorf("recorder: %s: %w", spec.OutputPath, err)
+	}
+	cue, err := newCueWriter(spec.OutputPath + ".cue")
+	if err != nil {
+		writer.Close()
+		return nil, fmt.Errorf("recorder: %s: %w", spec.OutputPath, err)
+	}
+
+	rv := &receiver{
+		spec:   spec,
+		writer: writer,
+		cue:    cue,
+		start:  time.Now(),
+	}
+
+	c, err := client.Dial(r.ctx, spec.Config, &rv.spec.Tuning)
+	if err != nil {
+		writer.Close()
+		cue.Close()
+		return nil, fmt.Errorf("recorder: dial %s: %w", spec.OutputPath, err)
+	}
+	rv.client = c
+	return rv, nil
+}
+
+// RemoveReceiver stops recording to outputPath, closing its connection and
+// finalizing its audio file's and cue file's headers.
+func (r *Recorder) RemoveReceiver(outputPath string) error {
+	r.mu.Lock()
+	rv, ok := r.receivers[outputPath]
+	if ok && rv == nil {
+		// AddReceiver's dial/file work for this path is still in flight;
+		// leave its reservation alone rather than deleting it out from
+		// under that call.
+		r.mu.Unlock()
+		return fmt.Errorf("recorder: %s is still being added", outputPath)
+	}
+	delete(r.receivers, outputPath)
+	r.mu.Unlock()
+	if !ok {
+		return fmt.Errorf("recorder: %s is not being recorded", outputPath)
+	}
+	rv.client.Close()
+	return nil
+}
+
+// Retune changes outputPath's receiver to a new Tuning without stopping its
+// file: a RETUNE cue is written instead, so the recording (and its cue
+// timeline) stays one continuous file across the frequency change.
+func (r *Recorder) Retune(outputPath string, tun client.Tuning) error {
+	r.mu.Lock()
+	rv, ok := r.receivers[outputPath]
+	r.mu.Unlock()
+	if !ok || rv == nil {
+		return fmt.Errorf("recorder: %s is not being recorded", outputPath)
+	}
+
+	rv.client.SetTuning(tun)
+	offset := rv.elapsed()
+
+	rv.client.Sendf("SET mod=%s low_cut=%d high_cut=%d freq=%.3f",
+		tun.ModeName, tun.LowCut, tun.HighCut, float64(tun.Freq+int64(tun.Offset))/1000.0)
+	rv.cue.Mark(offset, "RETUNE", tuningLabel(tun))
+	return nil
+}
+
+func (rv *receiver) elapsed() time.Duration {
+	return time.Since(rv.start)
+}
+
+// feed runs for the lifetime of the receiver, decoding upstream audio,
+// patching AudioPacket.Sequence gaps with silence, and appending the
+// result to the receiver's sampleWriter.
+func (rv *receiver) feed() {
+	defer rv.writer.Close()
+	defer rv.cue.Close()
+
+	ac := client.NewAudioClient(rv.client)
+	for ap := range ac.BackgroundPlayForDuration(365 * 24 * time.Hour) {
+		if ap.Reconnected {
+			// The session (and its sequence numbering) just restarted;
+			// forget lastSequence rather than treat the jump as missing
+			// packets, which could otherwise size a silence allocation
+			// proportional to however high the new session's sequence
+			// happens to start from.
+			rv.mu.Lock()
+			rv.haveSequence = false
+			rv.mu.Unlock()
+			rv.cue.Mark(rv.elapsed(), "RECONNECT", "")
+			continue
+		}
+
+		rv.mu.Lock()
+		var missing int
+		if rv.haveSequence {
+			missing = int(ap.Sequence - rv.lastSequence - 1)
+		}
+		if missing > maxGapPackets {
+			missing = maxGapPackets
+		}
+		gapStart := rv.elapsed()
+		packetLen := rv.packetLen
+		rv.lastSequence = ap.Sequence
+		rv.haveSequence = true
+		if len(ap.Samples) > 0 {
+			rv.packetLen = len(ap.Samples)
+		}
+		rv.mu.Unlock()
+
+		if missing > 0 && packetLen > 0 {
+			silence := make([]int16, missing*packetLen)
+			rv.writer.WriteSamples(silence)
+			rv.cue.Mark(gapStart, "GAP", fmt.Sprintf("%d missing packet(s)", missing))
+			rv.mu.Lock()
+			rv.samples += int64(len(silence))
+			rv.mu.Unlock()
+		}
+
+		rv.writer.WriteSamples(ap.Samples)
+		rv.mu.Lock()
+		rv.samples += int64(len(ap.Samples))
+		rv.mu.Unlock()
+	}
+}
+
+func tuningLabel(t client.Tuning) string {
+	return fmt.Sprintf("%.3fkHz %s", float64(t.Freq)/1000, t.ModeName)
+}
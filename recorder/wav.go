@@ -0,0 +1,95 @@
+package recorder
+
+import (
+	"encoding/binary"
+	"os"
+)
+
+const (
+	wavSampleRate    = 12000
+	wavChannels      = 1
+	wavBitsPerSample = 16
+)
+
+// wavWriter incrementally writes a RIFF/WAVE file: a placeholder header is
+// written first and patched with the final chunk sizes on Close, since the
+// total sample count isn't known until the recording ends.
+type wavWriter struct {
+	f         *os.File
+	dataBytes uint32
+}
+
+func newWavWriter(path string) (*wavWriter, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	w := &wavWriter{f: f}
+	if err := w.writeHeader(); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return w, nil
+}
+
+func (w *wavWriter) writeHeader() error {
+	byteRate := wavSampleRate * wavChannels * wavBitsPerSample / 8
+	blockAlign := wavChannels * wavBitsPerSample / 8
+
+	hdr := make([]byte, 44)
+	copy(hdr[0:4], "RIFF")
+	binary.LittleEndian.PutUint32(hdr[4:8], 36) // patched on Close
+	copy(hdr[8:12], "WAVE")
+	copy(hdr[12:16], "fmt ")
+	binary.LittleEndian.PutUint32(hdr[16:20], 16)
+	binary.LittleEndian.PutUint16(hdr[20:22], 1) // PCM
+	binary.LittleEndian.PutUint16(hdr[22:24], wavChannels)
+	binary.LittleEndian.PutUint32(hdr[24:28], wavSampleRate)
+	binary.LittleEndian.PutUint32(hdr[28:32], uint32(byteRate))
+	binary.LittleEndian.PutUint16(hdr[32:34], uint16(blockAlign))
+	binary.LittleEndian.PutUint16(hdr[34:36], wavBitsPerSample)
+	copy(hdr[36:40], "data")
+	binary.LittleEndian.PutUint32(hdr[40:44], 0) // patched on Close
+	_, err := w.f.Write(hdr)
+	return err
+}
+
+func (w *wavWriter) WriteSamples(samples []int16) error {
+	buf := make([]byte, 2*len(samples))
+	for i, s := range samples {
+		binary.LittleEndian.PutUint16(buf[2*i:], uint16(s))
+	}
+	if _, err := w.f.Write(buf); err != nil {
+		return err
+	}
+	w.dataBytes += uint32(len(buf))
+	return nil
+}
+
+// Close patches the RIFF and data chunk sizes now that the total is known,
+// then closes the file.
+func (w *wavWriter) Close() error {
+	if _, err := w.f.Seek(4, 0); err != nil {
+		w.f.Close()
+		return err
+	}
+	var riffSize [4]byte
+	binary.LittleEndian.PutUint32(riffSize[:], 36+w.dataBytes)
+	if _, err := w.f.Write(riffSize[:]); err != nil {
+		w.f.Close()
+		return err
+	}
+
+	if _, err := w.f.Seek(40, 0); err != nil {
+		w.f.Close()
+		return err
+	}
+	var dataSize [4]byte
+	binary.LittleEndian.PutUint32(dataSize[:], w.dataBytes)
+	if _, err := w.f.Write(dataSize[:]); err != nil {
+		w.f.Close()
+		return err
+	}
+
+	return w.f.Close()
+}
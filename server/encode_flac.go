@@ -0,0 +1,57 @@
+package server
+
+import "github.com/strickyak/go-kiwisdr-client/flac"
+
+// flacStreamBlockSize is the fixed number of samples per frame; smaller
+// than recorder's flacBlockSize so a slow listener's jitter buffer doesn't
+// have to wait as long for the first frame.
+const flacStreamBlockSize = 1024
+
+const (
+	flacChannels      = 1
+	flacBitsPerSample = 16
+)
+
+// flacEncoder wraps flac.FrameEncoder to satisfy the encoder interface for
+// a live, unbounded HTTP stream: unlike recorder.flacWriter, there's no
+// file to seek back into once the stream ends (it never does), so the
+// STREAMINFO block is built once, up front, with total_samples and the
+// frame-size bounds marked "unknown" (0) as the spec allows. blockSize is
+// otherwise constant, so minBlockSize/maxBlockSize are still exact.
+//
+// The header is never written into the ring buffer: every listener needs
+// it, not just whichever ones happen to be connected when it's produced,
+// so ServeHTTP fetches it via Header and sends it directly to each new
+// connection instead.
+type flacEncoder struct {
+	enc       flac.FrameEncoder
+	blockSize int
+	pending   []int16
+	header    []byte
+}
+
+func newFlacEncoder(sampleRate int) *flacEncoder {
+	e := &flacEncoder{
+		enc:       flac.FrameEncoder{SampleRate: uint32(sampleRate)},
+		blockSize: flacStreamBlockSize,
+	}
+	e.header = append(e.header, flac.MagicBytes...)
+	hdr := flac.MetadataBlockHeader(true, 0, 34)
+	e.header = append(e.header, hdr[:]...)
+	e.header = append(e.header, flac.EncodeStreamInfo(
+		uint16(e.blockSize), uint16(e.blockSize), 0, 0,
+		e.enc.SampleRate, flacChannels, flacBitsPerSample, 0, [16]byte{})...)
+	return e
+}
+
+func (e *flacEncoder) Encode(samples []int16) []byte {
+	var out []byte
+	e.pending = append(e.pending, samples...)
+	for len(e.pending) >= e.blockSize {
+		out = append(out, e.enc.EncodeFrame(e.pending[:e.blockSize])...)
+		e.pending = e.pending[e.blockSize:]
+	}
+	return out
+}
+
+func (e *flacEncoder) Header() []byte { return e.header }
@@ -0,0 +1,52 @@
+// +build mp3
+
+package server
+
+import (
+	"bytes"
+
+	"github.com/viert/lame"
+)
+
+// mp3Encoder wraps a lame.LameWriter: PCM bytes written to it come back out
+// of buf already MP3-encoded.
+type mp3Encoder struct {
+	buf bytes.Buffer
+	w   *lame.LameWriter
+}
+
+func init() {
+	newMp3EncoderFunc = func(bitrateKbps, sampleRate int) encoder {
+		return newMp3Encoder(bitrateKbps, sampleRate)
+	}
+}
+
+func newMp3Encoder(bitrateKbps, sampleRate int) *mp3Encoder {
+	e := &mp3Encoder{}
+	e.w = lame.NewWriter(&e.buf)
+	e.w.Encoder.SetInSamplerate(sampleRate)
+	e.w.Encoder.SetNumChannels(1)
+	e.w.Encoder.SetBitrate(bitrateKbps)
+	e.w.Encoder.SetMode(lame.MONO)
+	e.w.Encoder.InitParams()
+	return e
+}
+
+func (e *mp3Encoder) Encode(samples []int16) []byte {
+	pcm := make([]byte, 2*len(samples))
+	for i, s := range samples {
+		pcm[2*i] = byte(s)
+		pcm[2*i+1] = byte(s >> 8)
+	}
+	e.w.Write(pcm)
+
+	out := make([]byte, e.buf.Len())
+	copy(out, e.buf.Bytes())
+	e.buf.Reset()
+	return out
+}
+
+// Header is empty: each MP3 frame carries its own sync word and is
+// independently decodable, so a listener can join mid-stream with no
+// header at all.
+func (e *mp3Encoder) Header() []byte { return nil }
@@ -0,0 +1,7 @@
+// +build !mp3
+
+package server
+
+// newMp3EncoderFunc stays nil in default builds: MP3 encoding needs CGO and
+// libmp3lame, so it's only compiled in with the "mp3" build tag (see
+// encode_mp3.go). The rest of this module builds fine without them.
@@ -0,0 +1,61 @@
+package server
+
+import "log"
+
+// encoder turns a stream of resampled mono int16 PCM samples into the bytes
+// appended to a mount's ring buffer.
+type encoder interface {
+	// Encode consumes samples and returns any output bytes now ready to
+	// send.  Implementations may buffer internally: an mp3 or flac encoder
+	// needs a full frame's worth of samples before it can emit anything.
+	Encode(samples []int16) []byte
+
+	// Header returns the bytes, if any, a decoder needs before it can make
+	// sense of the Encode output that follows (e.g. FLAC's magic plus
+	// STREAMINFO block). They aren't written into the ring buffer, since a
+	// listener joining after ringSize bytes have gone by would never see
+	// them there; ServeHTTP sends them to each listener directly instead.
+	// pcm and mp3 need nothing here: mp3 frames are each independently
+	// decodable, and L16/PCM has no header at all.
+	Header() []byte
+}
+
+// newMp3EncoderFunc is set by encode_mp3.go's init, but only when built
+// with the "mp3" tag: MP3 support needs CGO and libmp3lame, so it's opt-in
+// the same way client/sink's PortAudioSink is gated behind "portaudio".
+// It stays nil in default builds (see encode_mp3_stub.go).
+var newMp3EncoderFunc func(bitrateKbps, sampleRate int) encoder
+
+func newEncoder(format string, bitrateKbps, sampleRate int) encoder {
+	switch format {
+	case "mp3":
+		if newMp3EncoderFunc == nil {
+			log.Printf("mp3 mount requested but this binary wasn't built with -tags mp3; serving pcm instead")
+			return newPcmEncoder()
+		}
+		return newMp3EncoderFunc(bitrateKbps, sampleRate)
+	case "flac":
+		return newFlacEncoder(sampleRate)
+	default:
+		return newPcmEncoder()
+	}
+}
+
+type pcmEncoder struct{}
+
+func newPcmEncoder() *pcmEncoder { return &pcmEncoder{} }
+
+// Encode packs samples as big-endian int16, matching AudioPacket.Samples'
+// own on-the-wire byte order (see AudioClient.ExtractAudioFromMessage).
+func (e *pcmEncoder) Encode(samples []int16) []byte {
+	out := make([]byte, 2*len(samples))
+	for i, s := range samples {
+		out[2*i] = byte(uint16(s) >> 8)
+		out[2*i+1] = byte(s)
+	}
+	return out
+}
+
+// Header is empty: raw L16/PCM has no container framing for a decoder to
+// need up front.
+func (e *pcmEncoder) Header() []byte { return nil }
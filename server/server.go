@@ -0,0 +1,315 @@
+// Package server implements an Icecast/ICY-compatible HTTP server that
+// fronts one or more KiwiSDR tunings as streaming mounts, e.g. "/740khz.mp3".
+//
+// Each mount wraps a single client.AudioClient; all listeners on that mount
+// share the one upstream websocket connection via a fan-out ring buffer, so
+// a slow listener is dropped rather than allowed to back-pressure the SDR.
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/strickyak/go-kiwisdr-client/client"
+)
+
+// Mount describes one KiwiSDR tuning exposed as an HTTP mount.
+type Mount struct {
+	Path    string // e.g. "/740khz.mp3"
+	Config  *client.Config
+	Tuning  *client.Tuning
+	Name    string // icy-name
+	Genre   string // icy-genre
+	Bitrate int    // icy-br, in kbps (mp3 only; ignored for pcm/flac)
+
+	// OutputRate is the sample rate, in Hz, that KiwiSDR's 12kHz mono
+	// stream is resampled to before encoding.  Zero picks a sane default
+	// for the mount's format (see Mount.Format).
+	OutputRate int
+}
+
+// Format is derived from the mount's path suffix: ".mp3", ".flac", or
+// (the default) raw "pcm".
+func (m Mount) Format() string {
+	switch {
+	case strings.HasSuffix(m.Path, ".mp3"):
+		return "mp3"
+	case strings.HasSuffix(m.Path, ".flac"):
+		return "flac"
+	default:
+		return "pcm"
+	}
+}
+
+// ringSize is the size, in encoded output bytes, of each mount's fan-out
+// buffer.  A listener that falls this far behind the upstream is dropped.
+const ringSize = 1 << 20 // 1 MiB
+
+// icyMetaInt is the number of stream bytes between ICY metadata blocks.
+const icyMetaInt = 16000
+
+type mountState struct {
+	mount  Mount
+	rate   int // resolved OutputRate
+	client *client.Client
+
+	// header is the encoder's Header() bytes, if any (e.g. FLAC's magic
+	// and STREAMINFO block). It's fixed for the mount's lifetime, so it's
+	// set once by AddMount before feed starts (so before the ring buffer
+	// sees any data) and is read unsynchronized thereafter.
+	header []byte
+
+	mu       sync.Mutex
+	cond     *sync.Cond
+	title    string
+	ring     [ringSize]byte
+	writePos int64 // monotonically increasing count of bytes ever written
+}
+
+// Server is an http.Handler exposing a set of Mounts.
+type Server struct {
+	// JitterBufferSeconds is the per-client jitter buffer: how far behind
+	// the live edge a new listener starts, in seconds of audio.
+	JitterBufferSeconds float64
+
+	mu     sync.Mutex
+	mounts map[string]*mountState
+}
+
+func NewServer(jitterBufferSeconds float64) *Server {
+	return &Server{
+		JitterBufferSeconds: jitterBufferSeconds,
+		mounts:              make(map[string]*mountState),
+	}
+}
+
+// AddMount dials the KiwiSDR for m.Tuning and starts feeding m.Path.  The
+// upstream connection (and its reconnect supervisor) runs for the life of
+// ctx; cancel ctx, or call Server.RemoveMount, to stop it.
+func (s *Server) AddMount(ctx context.Context, m Mount) error {
+	if m.Bitrate == 0 {
+		m.Bitrate = 48
+	}
+	if m.OutputRate == 0 {
+		if m.Format() == "mp3" {
+			m.OutputRate = 44100
+		} else {
+			m.OutputRate = 12000
+		}
+	}
+
+	ms := &mountState{mount: m, rate: m.OutputRate}
+	ms.cond = sync.NewCond(&ms.mu)
+
+	s.mu.Lock()
+	s.mounts[m.Path] = ms
+	s.mu.Unlock()
+
+	c, err := client.Dial(ctx, m.Config, m.Tuning)
+	if err != nil {
+		s.mu.Lock()
+		delete(s.mounts, m.Path)
+		s.mu.Unlock()
+		return err
+	}
+	ms.client = c
+	enc := newEncoder(m.Format(), m.Bitrate, m.OutputRate)
+	ms.header = enc.Header()
+	ac := client.NewAudioClient(c)
+	go ms.feed(ac, enc)
+	return nil
+}
+
+// RemoveMount closes the mount's upstream connection and stops serving path.
+func (s *Server) RemoveMount(path string) {
+	s.mu.Lock()
+	ms, ok := s.mounts[path]
+	delete(s.mounts, path)
+	s.mu.Unlock()
+	if ok {
+		ms.client.Close()
+	}
+}
+
+// feed runs for the lifetime of the mount, decoding upstream audio,
+// resampling and encoding it, and appending the result to the ring buffer
+// that every listener on this mount reads from.
+func (ms *mountState) feed(ac *client.AudioClient, enc encoder) {
+	for ap := range ac.BackgroundPlayForDuration(365 * 24 * time.Hour) {
+		ms.mu.Lock()
+		ms.title = fmt.Sprintf("%.3f kHz %s S%d", float64(ms.mount.Tuning.Freq)/1000, ms.mount.Tuning.ModeName, sMeterToS(ap.SMeter))
+		ms.mu.Unlock()
+
+		samples := resample(ap.Samples, 12000, ms.rate)
+		out := enc.Encode(samples)
+		if len(out) == 0 {
+			continue
+		}
+
+		ms.mu.Lock()
+		for _, b := range out {
+			ms.ring[ms.writePos%ringSize] = b
+			ms.writePos++
+		}
+		ms.cond.Broadcast()
+		ms.mu.Unlock()
+	}
+}
+
+func (ms *mountState) currentTitle() string {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	return ms.title
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	ms, ok := s.mounts[r.URL.Path]
+	s.mu.Unlock()
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	wantMeta := r.Header.Get("Icy-MetaData") == "1"
+
+	h := w.Header()
+	h.Set("icy-name", ms.mount.Name)
+	h.Set("icy-genre", ms.mount.Genre)
+	br := ms.mount.Bitrate
+	if ms.mount.Format() != "mp3" {
+		// Bitrate is mp3-only; report the stream's actual rate instead.
+		br = bytesPerSecond(ms.mount) * 8 / 1000
+	}
+	h.Set("icy-br", strconv.Itoa(br))
+	h.Set("Content-Type", contentType(ms.mount.Format()))
+	if wantMeta {
+		h.Set("icy-metaint", strconv.Itoa(icyMetaInt))
+	}
+	w.WriteHeader(http.StatusOK)
+	flusher, _ := w.(http.Flusher)
+
+	if len(ms.header) > 0 {
+		if _, err := w.Write(ms.header); err != nil {
+			return
+		}
+	}
+
+	ms.mu.Lock()
+	backlog := int64(s.JitterBufferSeconds * float64(bytesPerSecond(ms.mount)))
+	readPos := ms.writePos - backlog
+	if readPos < 0 {
+		readPos = 0
+	}
+	ms.mu.Unlock()
+
+	// The header bytes above are stream bytes too, so they count toward
+	// the first icyMetaInt.
+	sinceMeta := len(ms.header)
+	buf := make([]byte, 4096)
+	for {
+		ms.mu.Lock()
+		for ms.writePos == readPos {
+			ms.cond.Wait()
+		}
+		if ms.writePos-readPos > ringSize {
+			// Fell too far behind the upstream; drop this listener
+			// rather than let it back-pressure the SDR.
+			ms.mu.Unlock()
+			return
+		}
+		n := int(ms.writePos - readPos)
+		if n > len(buf) {
+			n = len(buf)
+		}
+		if wantMeta && n > icyMetaInt-sinceMeta {
+			n = icyMetaInt - sinceMeta
+		}
+		for i := 0; i < n; i++ {
+			buf[i] = ms.ring[readPos%ringSize]
+			readPos++
+		}
+		ms.mu.Unlock()
+
+		if _, err := w.Write(buf[:n]); err != nil {
+			return
+		}
+		sinceMeta += n
+		if wantMeta && sinceMeta >= icyMetaInt {
+			if err := writeIcyMeta(w, ms.currentTitle()); err != nil {
+				return
+			}
+			sinceMeta = 0
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+}
+
+// writeIcyMeta writes one ICY metadata block: a length byte (in units of 16
+// bytes) followed by a "StreamTitle='...';" string zero-padded to that length.
+func writeIcyMeta(w http.ResponseWriter, title string) error {
+	meta := fmt.Sprintf("StreamTitle='%s';", title)
+	blocks := (len(meta) + 15) / 16
+	padded := make([]byte, blocks*16)
+	copy(padded, meta)
+	if _, err := w.Write([]byte{byte(blocks)}); err != nil {
+		return err
+	}
+	_, err := w.Write(padded)
+	return err
+}
+
+func contentType(format string) string {
+	switch format {
+	case "mp3":
+		return "audio/mpeg"
+	case "flac":
+		return "audio/flac"
+	default:
+		return "audio/L16"
+	}
+}
+
+func bytesPerSecond(m Mount) int {
+	if m.Format() == "mp3" {
+		return m.Bitrate * 1000 / 8
+	}
+	return m.OutputRate * 2 // 16-bit mono PCM (or FLAC, approximated as PCM)
+}
+
+// sMeterToS buckets a raw AudioPacket.SMeter reading into an approximate
+// S-unit (0-9), for display in ICY StreamTitle metadata.
+func sMeterToS(smeter uint16) int {
+	s := int(smeter) / 6554 // 65535 / 10 buckets
+	if s > 9 {
+		s = 9
+	}
+	return s
+}
+
+// resample does simple linear-interpolation resampling from fromHz to toHz.
+func resample(in []int16, fromHz, toHz int) []int16 {
+	if fromHz == toHz || len(in) == 0 {
+		return in
+	}
+	n := len(in) * toHz / fromHz
+	out := make([]int16, n)
+	for i := range out {
+		srcPos := float64(i) * float64(fromHz) / float64(toHz)
+		i0 := int(srcPos)
+		if i0 >= len(in)-1 {
+			out[i] = in[len(in)-1]
+			continue
+		}
+		frac := srcPos - float64(i0)
+		out[i] = int16(float64(in[i0])*(1-frac) + float64(in[i0+1])*frac)
+	}
+	return out
+}